@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrKeyframeMismatch is returned when two keyframes of the same animated
+// geom can't be interpolated between, e.g. POLYGONs with different vertex
+// counts.
+var ErrKeyframeMismatch = errors.New("animated geom keyframes disagree on shape")
+
+// Tweener maps a keyframe-local progress in [0,1] to an eased progress in
+// [0,1]. linearTweener is the default; other easing curves can be added
+// later by implementing the same interface, including to drive CSG
+// parameter animation (e.g. opSmoothUnion's k).
+type Tweener interface {
+	Ease(t float64) float64
+}
+
+type linearTweener struct{}
+
+func (linearTweener) Ease(t float64) float64 { return t }
+
+// Keyframe pins a Geom's shape to a point on the scene's normalized
+// [0,1] timeline.
+type Keyframe struct {
+	t    float64
+	geom Geom
+}
+
+// AnimatedGeom is a WKT line carrying one or more `@t=` keyframes, e.g.
+// `POINT(0.5 0.5)@t=0 POINT(0.7 0.4)@t=1`. at interpolates between the
+// two keyframes bracketing a given t.
+type AnimatedGeom struct {
+	keyframes []Keyframe
+	tweener   Tweener
+}
+
+func (a AnimatedGeom) at(t float64) Geom {
+	if len(a.keyframes) == 0 {
+		return Geom{}
+	}
+	if t <= a.keyframes[0].t {
+		return a.keyframes[0].geom
+	}
+	last := a.keyframes[len(a.keyframes)-1]
+	if t >= last.t {
+		return last.geom
+	}
+	for i := 0; i+1 < len(a.keyframes); i++ {
+		k0, k1 := a.keyframes[i], a.keyframes[i+1]
+		if t < k0.t || t > k1.t {
+			continue
+		}
+		local := 0.0
+		if span := k1.t - k0.t; span > 0 {
+			local = (t - k0.t) / span
+		}
+		return lerpGeom(k0.geom, k1.geom, a.tweener.Ease(local))
+	}
+	return last.geom
+}
+
+// Scene is everything parsed from a WKT file: the geoms that never
+// change, plus the ones animated across keyframes.
+type Scene struct {
+	geoms    []Geom
+	animated []AnimatedGeom
+}
+
+// at flattens the scene into the []Geom sdRender already knows how to
+// draw, evaluating every animated geom at normalized time t.
+func (s Scene) at(t float64) []Geom {
+	geoms := make([]Geom, len(s.geoms), len(s.geoms)+len(s.animated))
+	copy(geoms, s.geoms)
+	for _, ag := range s.animated {
+		geoms = append(geoms, ag.at(t))
+	}
+	return geoms
+}
+
+func lerpF(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+func lerpPoint(a, b Point, t float64) Point {
+	return Point{lerpF(a.x, b.x, t), lerpF(a.y, b.y, t)}
+}
+
+// lerpGeom interpolates two Geoms of the same geomType. CSG combinators
+// recurse into their children so a whole subtree can be keyframed.
+func lerpGeom(a, b Geom, t float64) Geom {
+	out := a
+	out.roundR = lerpF(a.roundR, b.roundR, t)
+	switch a.geomType {
+	case PointType:
+		out.point = lerpPoint(a.point, b.point, t)
+	case SegmentType:
+		out.segment = Segment{lerpPoint(a.segment.a, b.segment.a, t), lerpPoint(a.segment.b, b.segment.b, t)}
+	case CircleType:
+		out.circle = Circle{lerpPoint(a.circle.center, b.circle.center, t), lerpF(a.circle.r, b.circle.r, t)}
+	case BoxType:
+		out.box = Box{lerpPoint(a.box.center, b.box.center, t), lerpPoint(a.box.half, b.box.half, t)}
+	case TriangleType:
+		out.triangle = Triangle{
+			lerpPoint(a.triangle.a, b.triangle.a, t),
+			lerpPoint(a.triangle.b, b.triangle.b, t),
+			lerpPoint(a.triangle.c, b.triangle.c, t),
+		}
+	case ArcType:
+		out.arc = Arc{
+			center:   lerpPoint(a.arc.center, b.arc.center, t),
+			r:        lerpF(a.arc.r, b.arc.r, t),
+			aperture: lerpF(a.arc.aperture, b.arc.aperture, t),
+			rotation: lerpF(a.arc.rotation, b.arc.rotation, t),
+		}
+	case QuadraticBezierType:
+		out.bezier = QuadraticBezier{
+			lerpPoint(a.bezier.a, b.bezier.a, t),
+			lerpPoint(a.bezier.b, b.bezier.b, t),
+			lerpPoint(a.bezier.c, b.bezier.c, t),
+		}
+	case PolygonType:
+		points := make([]Point, len(a.polygon.points))
+		for i := range points {
+			points[i] = lerpPoint(a.polygon.points[i], b.polygon.points[i], t)
+		}
+		out.polygon = Polygon{points: points}
+	case UnionType, SubtractType, IntersectType, SmoothUnionType:
+		left := lerpGeom(*a.children[0], *b.children[0], t)
+		right := lerpGeom(*a.children[1], *b.children[1], t)
+		out.children = []*Geom{&left, &right}
+		out.k = lerpF(a.k, b.k, t)
+	}
+	return out
+}
+
+// parseAnimatedLine parses a WKT line carrying one or more `@t=` keyframe
+// markers, e.g. `POINT(0.5 0.5)@t=0 POINT(0.7 0.4)@t=1`.
+func parseAnimatedLine(line string) (AnimatedGeom, error) {
+	ag := AnimatedGeom{tweener: linearTweener{}}
+	var sameShape func(a, b *Geom) bool
+	sameShape = func(a, b *Geom) bool {
+		if a.geomType != b.geomType || len(a.polygon.points) != len(b.polygon.points) || len(a.children) != len(b.children) {
+			return false
+		}
+		for i := range a.children {
+			if !sameShape(a.children[i], b.children[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	cursor := 0
+	for cursor < len(line) {
+		geo, err := parseExpr(line, &cursor)
+		if err != nil {
+			return ag, err
+		}
+		if !strings.HasPrefix(line[cursor:], "@t=") {
+			return ag, ErrUnsupportedWKT
+		}
+		cursor += 3 // Skip @t=
+		t, err := parseNumber(line, &cursor)
+		if err != nil {
+			return ag, err
+		}
+		if len(ag.keyframes) > 0 && !sameShape(&ag.keyframes[0].geom, geo) {
+			return ag, ErrKeyframeMismatch
+		}
+		ag.keyframes = append(ag.keyframes, Keyframe{t: t, geom: *geo})
+		if cursor < len(line) && line[cursor] == ' ' {
+			cursor++ // Skip the separator space
+		}
+	}
+	sort.Slice(ag.keyframes, func(i, j int) bool { return ag.keyframes[i].t < ag.keyframes[j].t })
+	return ag, nil
+}
+
+// loadScene reads a WKT file into a Scene, routing every line with an
+// `@t=` keyframe marker into an AnimatedGeom and everything else into
+// the static geoms, the same way wktSource.Load reads a plain scene.
+func loadScene(path string) (Scene, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Scene{}, err
+	}
+	defer file.Close()
+
+	var scene Scene
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, "@t=") {
+			ag, err := parseAnimatedLine(line)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			scene.animated = append(scene.animated, ag)
+			continue
+		}
+		geo, err := parseLine(line)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		scene.geoms = append(scene.geoms, geo)
+	}
+	return scene, scanner.Err()
+}
+
+// RenderSequence renders nFrames evenly spaced across the scene's
+// normalized [0,1] timeline and feeds them, as raw bgr24 frames, to an
+// `ffmpeg` process that muxes them into outputFile (ffmpeg picks the
+// container/codec from its extension, so both .mp4 and .gif work). out
+// receives ffmpeg's own logging. When ffmpeg isn't on PATH, it falls back
+// to writing a numbered sequence of BMP files named after outputFile
+// instead.
+func RenderSequence(scene Scene, nFrames int, outputFile string, out io.Writer) error {
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return renderSequenceToFiles(scene, nFrames, outputFile)
+	}
+
+	cmd := exec.Command(ffmpegPath,
+		"-f", "rawvideo",
+		"-pix_fmt", "bgr24",
+		"-s", fmt.Sprintf("%dx%d", CanvasWidth, CanvasHeight),
+		"-r", "30",
+		"-i", "-",
+		"-y", outputFile,
+	)
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	for frame := 0; frame < nFrames; frame++ {
+		pixels := renderTiles(scene.at(frameTime(frame, nFrames)), CanvasWidth, CanvasHeight, RenderOptions{})
+		if _, err := stdin.Write(rgbaToBGR24(pixels)); err != nil {
+			stdin.Close()
+			cmd.Wait()
+			return err
+		}
+	}
+	if err := stdin.Close(); err != nil {
+		return err
+	}
+	return cmd.Wait()
+}
+
+func frameTime(frame, nFrames int) float64 {
+	if nFrames <= 1 {
+		return 0
+	}
+	return float64(frame) / float64(nFrames-1)
+}
+
+func renderSequenceToFiles(scene Scene, nFrames int, outputFile string) error {
+	base := strings.TrimSuffix(filepath.Base(outputFile), filepath.Ext(outputFile))
+	for frame := 0; frame < nFrames; frame++ {
+		pixels := renderTiles(scene.at(frameTime(frame, nFrames)), CanvasWidth, CanvasHeight, RenderOptions{})
+
+		name := fmt.Sprintf("%s_%04d.bmp", base, frame)
+		file, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		err = (bmpEncoder{}).Encode(file, pixels, CanvasWidth, CanvasHeight)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rgbaToBGR24 composites a straight-alpha RGBA buffer onto black and
+// packs it into bgr24, the pixel format ffmpeg is told to expect.
+func rgbaToBGR24(pixels []byte) []byte {
+	bgr := make([]byte, len(pixels)/4*3)
+	for i, j := 0, 0; i < len(pixels); i, j = i+4, j+3 {
+		a := float64(pixels[i+3]) / 255
+		bgr[j+0] = byte(float64(pixels[i+2]) * a)
+		bgr[j+1] = byte(float64(pixels[i+1]) * a)
+		bgr[j+2] = byte(float64(pixels[i+0]) * a)
+	}
+	return bgr
+}
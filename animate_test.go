@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseAnimatedLinePolygonVertexMismatch(t *testing.T) {
+	line := "POLYGON(0 0, 0.1 0, 0.1 0.1, 0 0.1)@t=0 POLYGON(0 0, 0.1 0, 0.1 0.1)@t=1"
+	if _, err := parseAnimatedLine(line); err != ErrKeyframeMismatch {
+		t.Fatalf("got err %v, want ErrKeyframeMismatch", err)
+	}
+}
+
+func TestParseAnimatedLinePolygonSameVertexCount(t *testing.T) {
+	line := "POLYGON(0 0, 0.1 0, 0.1 0.1)@t=0 POLYGON(0.1 0.1, 0.2 0.1, 0.2 0.2)@t=1"
+	ag, err := parseAnimatedLine(line)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ag.keyframes) != 2 {
+		t.Fatalf("got %d keyframes, want 2", len(ag.keyframes))
+	}
+	mid := ag.at(0.5)
+	if len(mid.polygon.points) != 3 {
+		t.Errorf("got %d interpolated points, want 3", len(mid.polygon.points))
+	}
+}
+
+func TestRenderSequenceToFilesUsesOutputBasename(t *testing.T) {
+	dir := t.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+	defer os.Chdir(cwd)
+
+	scene := Scene{geoms: []Geom{{geomType: CircleType, circle: Circle{center: Point{CanvasWidth / 2, CanvasHeight / 2}, r: 10}}}}
+	if err := renderSequenceToFiles(scene, 2, "myanimation.gif"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, frame := range []string{"myanimation_0000.bmp", "myanimation_0001.bmp"} {
+		if _, err := os.Stat(filepath.Join(dir, frame)); err != nil {
+			t.Errorf("expected %s to exist: %v", frame, err)
+		}
+	}
+}
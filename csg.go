@@ -0,0 +1,494 @@
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+// Circle, Box, Triangle, Arc, QuadraticBezier and Polygon hold the shape
+// data for the geomTypes of the same name, the same way Point and Segment
+// already do for PointType and SegmentType.
+type Circle struct {
+	center Point
+	r      float64
+}
+
+type Box struct {
+	center Point
+	half   Point
+}
+
+type Triangle struct {
+	a, b, c Point
+}
+
+type Arc struct {
+	center                Point
+	r, aperture, rotation float64
+}
+
+type QuadraticBezier struct {
+	a, b, c Point
+}
+
+type Polygon struct {
+	points []Point
+}
+
+// add function
+func add(a, b Point) Point {
+	return Point{a.x + b.x, a.y + b.y}
+}
+
+// dot2 function
+func dot2(p Point) float64 {
+	return dot(p, p)
+}
+
+// abs function
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// sign function
+func sign(v float64) float64 {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// sdCircle function
+func sdCircle(p Point, center Point, r float64) float64 {
+	return length(sub(p, center)) - r
+}
+
+// sdBox function
+func sdBox(p Point, center Point, half Point) float64 {
+	d := Point{abs(p.x-center.x) - half.x, abs(p.y-center.y) - half.y}
+	outside := length(Point{max(d.x, 0), max(d.y, 0)})
+	inside := min(max(d.x, d.y), 0)
+	return outside + inside
+}
+
+// sdTriangle function
+func sdTriangle(p, a, b, c Point) float64 {
+	e0, e1, e2 := sub(b, a), sub(c, b), sub(a, c)
+	v0, v1, v2 := sub(p, a), sub(p, b), sub(p, c)
+	pq0 := sub(v0, mul(e0, clamp(dot(v0, e0)/dot(e0, e0), 0, 1)))
+	pq1 := sub(v1, mul(e1, clamp(dot(v1, e1)/dot(e1, e1), 0, 1)))
+	pq2 := sub(v2, mul(e2, clamp(dot(v2, e2)/dot(e2, e2), 0, 1)))
+
+	s := sign(e0.x*e2.y - e0.y*e2.x)
+	cross0 := s * (v0.x*e0.y - v0.y*e0.x)
+	cross1 := s * (v1.x*e1.y - v1.y*e1.x)
+	cross2 := s * (v2.x*e2.y - v2.y*e2.x)
+
+	d := min(min(dot2(pq0), dot2(pq1)), dot2(pq2))
+	inside := min(min(cross0, cross1), cross2)
+	return -math.Sqrt(d) * sign(inside)
+}
+
+// sdArc function. p is rotated into the arc's local frame, where the arc
+// is mirrored about the y-axis and centred on the positive y-axis with
+// half-aperture angle aperture, before the standard two-case ring distance is applied.
+func sdArc(p Point, center Point, r, aperture, rotation float64) float64 {
+	rel := sub(p, center)
+	cr, sr := math.Cos(-rotation), math.Sin(-rotation)
+	local := Point{rel.x*cr - rel.y*sr, rel.x*sr + rel.y*cr}
+	local.x = abs(local.x)
+
+	sc := Point{math.Sin(aperture), math.Cos(aperture)}
+	if sc.y*local.x > sc.x*local.y {
+		return length(sub(local, mul(sc, r)))
+	}
+	return abs(length(local) - r)
+}
+
+// sdQuadraticBezier function, after Inigo Quilez's closed-form solution:
+// the nearest point on the curve is found by solving the cubic root
+// equation on the derivative of the squared distance.
+func sdQuadraticBezier(pos, ctrlA, ctrlB, ctrlC Point) float64 {
+	a := sub(ctrlB, ctrlA)
+	b := add(sub(ctrlA, mul(ctrlB, 2)), ctrlC)
+	c := mul(a, 2)
+	d := sub(ctrlA, pos)
+
+	kk := 1.0 / dot(b, b)
+	kx := kk * dot(a, b)
+	ky := kk * (2*dot(a, a) + dot(d, b)) / 3
+	kz := kk * dot(d, a)
+
+	res := 0.0
+	p := ky - kx*kx
+	p3 := p * p * p
+	q := kx*(2*kx*kx-3*ky) + kz
+	h := q*q + 4*p3
+
+	if h >= 0 {
+		h = math.Sqrt(h)
+		hx, hy := (h-q)/2, (-h-q)/2
+		ux := sign(hx) * math.Pow(abs(hx), 1.0/3.0)
+		uy := sign(hy) * math.Pow(abs(hy), 1.0/3.0)
+		t := clamp(ux+uy-kx, 0, 1)
+		res = dot2(add(d, mul(add(c, mul(b, t)), t)))
+	} else {
+		z := math.Sqrt(-p)
+		v := math.Acos(q/(p*z*2)) / 3
+		m := math.Cos(v)
+		n := math.Sin(v) * 1.732050808
+		t0 := clamp((m+m)*z-kx, 0, 1)
+		t1 := clamp((-n-m)*z-kx, 0, 1)
+		res = min(
+			dot2(add(d, mul(add(c, mul(b, t0)), t0))),
+			dot2(add(d, mul(add(c, mul(b, t1)), t1))),
+		)
+	}
+	return math.Sqrt(res)
+}
+
+// sdPolygon function: signed distance via winding-number parity (for the
+// sign) combined with the minimum distance to any edge segment.
+func sdPolygon(p Point, v []Point) float64 {
+	n := len(v)
+	d := dot2(sub(p, v[0]))
+	s := 1.0
+	j := n - 1
+	for i := 0; i < n; i++ {
+		e := sub(v[j], v[i])
+		w := sub(p, v[i])
+		b := sub(w, mul(e, clamp(dot(w, e)/dot(e, e), 0, 1)))
+		d = min(d, dot2(b))
+
+		above := p.y >= v[i].y
+		wasAbove := p.y < v[j].y
+		crossing := e.x*w.y > e.y*w.x
+		if (above && wasAbove && crossing) || (!above && !wasAbove && !crossing) {
+			s = -s
+		}
+		j = i
+	}
+	return s * math.Sqrt(d)
+}
+
+// opUnion function
+func opUnion(d1, d2 float64) float64 {
+	return min(d1, d2)
+}
+
+// opSubtract function: d1 with d2 cut out of it
+func opSubtract(d1, d2 float64) float64 {
+	return max(d1, -d2)
+}
+
+// opIntersect function
+func opIntersect(d1, d2 float64) float64 {
+	return max(d1, d2)
+}
+
+// opSmoothUnion function
+func opSmoothUnion(d1, d2, k float64) float64 {
+	h := max(k-abs(d1-d2), 0)
+	return min(d1, d2) - 0.25*h*h/k
+}
+
+// sdGeom evaluates a Geom's signed distance at p, recursing into
+// children for the CSG combinator types.
+func sdGeom(g *Geom, p Point) float64 {
+	switch g.geomType {
+	case PointType:
+		return opRound(sdPoint(p, g.point), g.roundR)
+	case SegmentType:
+		return opRound(sdSegment(p, g.segment.a, g.segment.b), g.roundR)
+	case CircleType:
+		return opRound(sdCircle(p, g.circle.center, g.circle.r), g.roundR)
+	case BoxType:
+		return opRound(sdBox(p, g.box.center, g.box.half), g.roundR)
+	case TriangleType:
+		return opRound(sdTriangle(p, g.triangle.a, g.triangle.b, g.triangle.c), g.roundR)
+	case ArcType:
+		return opRound(sdArc(p, g.arc.center, g.arc.r, g.arc.aperture, g.arc.rotation), g.roundR)
+	case QuadraticBezierType:
+		return opRound(sdQuadraticBezier(p, g.bezier.a, g.bezier.b, g.bezier.c), g.roundR)
+	case PolygonType:
+		return opRound(sdPolygon(p, g.polygon.points), g.roundR)
+	case UnionType:
+		return opUnion(sdGeom(g.children[0], p), sdGeom(g.children[1], p))
+	case SubtractType:
+		return opSubtract(sdGeom(g.children[0], p), sdGeom(g.children[1], p))
+	case IntersectType:
+		return opIntersect(sdGeom(g.children[0], p), sdGeom(g.children[1], p))
+	case SmoothUnionType:
+		return opSmoothUnion(sdGeom(g.children[0], p), sdGeom(g.children[1], p), g.k)
+	default:
+		return math.Inf(1)
+	}
+}
+
+// parseExpr parses one WKT geometry expression starting at *cursor,
+// dispatching on its keyword the same way parseLine's switch used to.
+// It is recursive so that CSG wrappers (UNION, DIFF, INTERSECT, SMOOTH)
+// can take other expressions, including further wrappers, as arguments.
+func parseExpr(line string, cursor *int) (*Geom, error) {
+	rest := line[*cursor:]
+	switch {
+	case strings.HasPrefix(rest, "POINT"):
+		point, err := parsePoint(line, cursor)
+		if err != nil {
+			return nil, err
+		}
+		return &Geom{geomType: PointType, point: point}, nil
+	case strings.HasPrefix(rest, "SEGMENT"):
+		segment, err := parseSegment(line, cursor)
+		if err != nil {
+			return nil, err
+		}
+		return &Geom{geomType: SegmentType, segment: segment}, nil
+	case strings.HasPrefix(rest, "ROUND"):
+		geo, err := parseRound(line, cursor)
+		if err != nil {
+			return nil, err
+		}
+		return &geo, nil
+	case strings.HasPrefix(rest, "CIRCLE"):
+		return parseCircle(line, cursor)
+	case strings.HasPrefix(rest, "TRIANGLE"):
+		return parseTriangle(line, cursor)
+	case strings.HasPrefix(rest, "BOX"):
+		return parseBox(line, cursor)
+	case strings.HasPrefix(rest, "ARC"):
+		return parseArc(line, cursor)
+	case strings.HasPrefix(rest, "BEZIER"):
+		return parseBezier(line, cursor)
+	case strings.HasPrefix(rest, "POLYGON"):
+		return parsePolygon(line, cursor)
+	case strings.HasPrefix(rest, "UNION"):
+		return parseCombinator(line, cursor, UnionType, len("UNION("))
+	case strings.HasPrefix(rest, "DIFF"):
+		return parseCombinator(line, cursor, SubtractType, len("DIFF("))
+	case strings.HasPrefix(rest, "INTERSECT"):
+		return parseCombinator(line, cursor, IntersectType, len("INTERSECT("))
+	case strings.HasPrefix(rest, "SMOOTH"):
+		return parseSmooth(line, cursor)
+	default:
+		return nil, ErrUnsupportedWKT
+	}
+}
+
+func parseCircle(line string, cursor *int) (*Geom, error) {
+	*cursor += 7 // Skip CIRCLE(
+	cx, err := parseNumber(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	*cursor++ // Skip the separator space
+	cy, err := parseNumber(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	*cursor++ // Skip the separator space
+	r, err := parseNumber(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	*cursor++ // Skip the )
+	center := Point{cx * CanvasWidth, cy * CanvasHeight}
+	return &Geom{geomType: CircleType, circle: Circle{center: center, r: r * CanvasDiag}}, nil
+}
+
+func parseBox(line string, cursor *int) (*Geom, error) {
+	*cursor += 4 // Skip BOX(
+	cx, err := parseNumber(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	*cursor++ // Skip the separator space
+	cy, err := parseNumber(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	*cursor++ // Skip the separator space
+	hx, err := parseNumber(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	*cursor++ // Skip the separator space
+	hy, err := parseNumber(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	*cursor++ // Skip the )
+	center := Point{cx * CanvasWidth, cy * CanvasHeight}
+	half := Point{hx * CanvasWidth, hy * CanvasHeight}
+	return &Geom{geomType: BoxType, box: Box{center: center, half: half}}, nil
+}
+
+func parseTriangle(line string, cursor *int) (*Geom, error) {
+	*cursor += 9 // Skip TRIANGLE(
+	points := make([]Point, 3)
+	for i := range points {
+		if i > 0 {
+			*cursor += 2 // Skip the ", " separator
+		}
+		x, err := parseNumber(line, cursor)
+		if err != nil {
+			return nil, err
+		}
+		*cursor++ // Skip the separator space
+		y, err := parseNumber(line, cursor)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = Point{x * CanvasWidth, y * CanvasHeight}
+	}
+	*cursor++ // Skip the )
+	return &Geom{geomType: TriangleType, triangle: Triangle{a: points[0], b: points[1], c: points[2]}}, nil
+}
+
+func parseArc(line string, cursor *int) (*Geom, error) {
+	*cursor += 4 // Skip ARC(
+	cx, err := parseNumber(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	*cursor++ // Skip the separator space
+	cy, err := parseNumber(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	*cursor++ // Skip the separator space
+	r, err := parseNumber(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	*cursor++ // Skip the separator space
+	aperture, err := parseNumber(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	*cursor++ // Skip the separator space
+	rotation, err := parseNumber(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	*cursor++ // Skip the )
+	center := Point{cx * CanvasWidth, cy * CanvasHeight}
+	return &Geom{geomType: ArcType, arc: Arc{center: center, r: r * CanvasDiag, aperture: aperture, rotation: rotation}}, nil
+}
+
+func parseBezier(line string, cursor *int) (*Geom, error) {
+	*cursor += 7 // Skip BEZIER(
+	points := make([]Point, 3)
+	for i := range points {
+		if i > 0 {
+			*cursor += 2 // Skip the ", " separator
+		}
+		x, err := parseNumber(line, cursor)
+		if err != nil {
+			return nil, err
+		}
+		*cursor++ // Skip the separator space
+		y, err := parseNumber(line, cursor)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = Point{x * CanvasWidth, y * CanvasHeight}
+	}
+	*cursor++ // Skip the )
+	return &Geom{geomType: QuadraticBezierType, bezier: QuadraticBezier{a: points[0], b: points[1], c: points[2]}}, nil
+}
+
+func parsePolygon(line string, cursor *int) (*Geom, error) {
+	*cursor += 8 // Skip POLYGON(
+	var points []Point
+	for {
+		if len(points) > 0 {
+			*cursor += 2 // Skip the ", " separator
+		}
+		x, err := parseNumber(line, cursor)
+		if err != nil {
+			return nil, err
+		}
+		*cursor++ // Skip the separator space
+		y, err := parseNumber(line, cursor)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, Point{x * CanvasWidth, y * CanvasHeight})
+		if *cursor >= len(line) || line[*cursor] != ',' {
+			break
+		}
+	}
+	*cursor++ // Skip the )
+	if len(points) < 3 {
+		return nil, ErrUnsupportedWKT
+	}
+	return &Geom{geomType: PolygonType, polygon: Polygon{points: points}}, nil
+}
+
+// expectLiteral consumes the literal lit at *cursor, returning
+// ErrUnsupportedWKT instead of advancing past the end of line if lit
+// isn't actually there — callers would otherwise run their next slice
+// past len(line) on a truncated or mistyped expression.
+func expectLiteral(line string, cursor *int, lit string) error {
+	if *cursor+len(lit) > len(line) || line[*cursor:*cursor+len(lit)] != lit {
+		return ErrUnsupportedWKT
+	}
+	*cursor += len(lit)
+	return nil
+}
+
+func parseCombinator(line string, cursor *int, opType, prefixLen int) (*Geom, error) {
+	*cursor += prefixLen
+	left, err := parseExpr(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectLiteral(line, cursor, ", "); err != nil {
+		return nil, err
+	}
+	right, err := parseExpr(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectLiteral(line, cursor, ")"); err != nil {
+		return nil, err
+	}
+	return &Geom{geomType: opType, children: []*Geom{left, right}}, nil
+}
+
+func parseSmooth(line string, cursor *int) (*Geom, error) {
+	*cursor += 7 // Skip SMOOTH(
+	left, err := parseExpr(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectLiteral(line, cursor, ", "); err != nil {
+		return nil, err
+	}
+	right, err := parseExpr(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectLiteral(line, cursor, ", "); err != nil {
+		return nil, err
+	}
+	k, err := parseNumber(line, cursor)
+	if err != nil {
+		return nil, err
+	}
+	if k <= 0 {
+		return nil, ErrUnsupportedWKT
+	}
+	if err := expectLiteral(line, cursor, ")"); err != nil {
+		return nil, err
+	}
+	return &Geom{geomType: SmoothUnionType, children: []*Geom{left, right}, k: k}, nil
+}
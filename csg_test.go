@@ -0,0 +1,141 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestSdCircle(t *testing.T) {
+	center := Point{0, 0}
+	if d := sdCircle(Point{0, 0}, center, 5); !almostEqual(d, -5) {
+		t.Errorf("center: got %v, want -5", d)
+	}
+	if d := sdCircle(Point{5, 0}, center, 5); !almostEqual(d, 0) {
+		t.Errorf("on edge: got %v, want 0", d)
+	}
+	if d := sdCircle(Point{10, 0}, center, 5); !almostEqual(d, 5) {
+		t.Errorf("outside: got %v, want 5", d)
+	}
+}
+
+func TestSdBox(t *testing.T) {
+	center := Point{0, 0}
+	half := Point{2, 1}
+	if d := sdBox(Point{0, 0}, center, half); d >= 0 {
+		t.Errorf("center should be inside, got %v", d)
+	}
+	if d := sdBox(Point{10, 0}, center, half); !almostEqual(d, 8) {
+		t.Errorf("outside along x: got %v, want 8", d)
+	}
+}
+
+func TestSdTriangle(t *testing.T) {
+	a, b, c := Point{0, 0}, Point{10, 0}, Point{0, 10}
+	if d := sdTriangle(Point{1, 1}, a, b, c); d >= 0 {
+		t.Errorf("inside triangle should be negative, got %v", d)
+	}
+	if d := sdTriangle(Point{-5, -5}, a, b, c); d <= 0 {
+		t.Errorf("outside triangle should be positive, got %v", d)
+	}
+}
+
+func TestSdPolygon(t *testing.T) {
+	square := []Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	if d := sdPolygon(Point{5, 5}, square); d >= 0 {
+		t.Errorf("inside square should be negative, got %v", d)
+	}
+	if d := sdPolygon(Point{20, 5}, square); !almostEqual(d, 10) {
+		t.Errorf("outside square: got %v, want 10", d)
+	}
+}
+
+func TestSdArc(t *testing.T) {
+	center := Point{0, 0}
+	// A half-circle centred on +y; its midpoint (0, 5) should sit on the ring.
+	if d := sdArc(Point{0, 5}, center, 5, math.Pi/2, 0); !almostEqual(d, 0) {
+		t.Errorf("on ring: got %v, want 0", d)
+	}
+}
+
+func TestSdQuadraticBezier(t *testing.T) {
+	a, b, c := Point{0, 0}, Point{5, 10}, Point{10, 0}
+	if d := sdQuadraticBezier(a, a, b, c); !almostEqual(d, 0) {
+		t.Errorf("endpoint should lie on curve, got %v", d)
+	}
+	if d := sdQuadraticBezier(Point{5, -10}, a, b, c); d <= 0 {
+		t.Errorf("expected a positive distance, got %v", d)
+	}
+}
+
+func TestOpUnion(t *testing.T) {
+	if d := opUnion(-1, 2); d != -1 {
+		t.Errorf("got %v, want -1", d)
+	}
+}
+
+func TestOpSubtract(t *testing.T) {
+	if d := opSubtract(-5, -1); d != 1 {
+		t.Errorf("got %v, want 1", d)
+	}
+}
+
+func TestOpIntersect(t *testing.T) {
+	if d := opIntersect(-1, 2); d != 2 {
+		t.Errorf("got %v, want 2", d)
+	}
+}
+
+func TestOpSmoothUnion(t *testing.T) {
+	// Far apart, smoothing shouldn't change the result much.
+	got := opSmoothUnion(-1, 100, 0.01)
+	if !almostEqual(got, -1) {
+		t.Errorf("got %v, want close to -1", got)
+	}
+	// At equal distances, the smoothing term should pull d below min(d1,d2).
+	if got := opSmoothUnion(1, 1, 2); got >= 1 {
+		t.Errorf("expected smoothing to round the corner, got %v", got)
+	}
+}
+
+func TestParseCSGExpr(t *testing.T) {
+	geo, err := parseLine("UNION(CIRCLE(0.5 0.5 0.1), BOX(0.5 0.5 0.1 0.1))")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if geo.geomType != UnionType {
+		t.Fatalf("got geomType %v, want UnionType", geo.geomType)
+	}
+	if len(geo.children) != 2 {
+		t.Fatalf("got %d children, want 2", len(geo.children))
+	}
+	d := sdGeom(&geo, Point{0.5 * CanvasWidth, 0.5 * CanvasHeight})
+	if d >= 0 {
+		t.Errorf("center of union should be inside, got %v", d)
+	}
+}
+
+func TestParseCSGExprMalformed(t *testing.T) {
+	cases := []string{
+		"UNION(POINT(0.5 0.5))",           // extra closing paren, no second argument
+		"DIFF(CIRCLE(0.5 0.5 0.1))",       // missing second argument entirely
+		"SMOOTH(CIRCLE(0.5 0.5 0.1), CIRCLE(0.4 0.4 0.1)", // missing k and closing paren
+	}
+	for _, line := range cases {
+		if _, err := parseLine(line); err == nil {
+			t.Errorf("parseLine(%q): expected an error, got nil", line)
+		}
+	}
+}
+
+func TestParseSmoothRejectsNonPositiveK(t *testing.T) {
+	if _, err := parseLine("SMOOTH(CIRCLE(0.5 0.5 0.1), CIRCLE(0.4 0.4 0.1), 0)"); err == nil {
+		t.Errorf("expected an error for k=0, got nil")
+	}
+	if _, err := parseLine("SMOOTH(CIRCLE(0.5 0.5 0.1), CIRCLE(0.4 0.4 0.1), -1)"); err == nil {
+		t.Errorf("expected an error for negative k, got nil")
+	}
+}
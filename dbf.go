@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// parseDBF reads the xBase records of a .dbf file into one
+// field-name -> value map per record, decoding text fields with the
+// encoding named by encoding (typically the contents of a sibling .cpg).
+func parseDBF(data []byte, encoding string) ([]map[string]string, error) {
+	if len(data) < 32 {
+		return nil, errors.New("dbf header truncated")
+	}
+
+	numRecords := int(binary.LittleEndian.Uint32(data[4:8]))
+	headerLen := int(binary.LittleEndian.Uint16(data[8:10]))
+	recordLen := int(binary.LittleEndian.Uint16(data[10:12]))
+
+	type field struct {
+		name string
+		off  int
+		len  int
+	}
+	var fields []field
+	off := 0
+	for pos := 32; pos+1 <= len(data) && data[pos] != 0x0D; pos += 32 {
+		if pos+32 > len(data) {
+			break
+		}
+		name := strings.TrimRight(string(data[pos:pos+11]), "\x00")
+		fieldLen := int(data[pos+16])
+		fields = append(fields, field{name: strings.ToLower(name), off: off, len: fieldLen})
+		off += fieldLen
+	}
+
+	decode := dbfDecoder(encoding)
+
+	records := make([]map[string]string, 0, numRecords)
+	recStart := headerLen
+	for i := 0; i < numRecords; i++ {
+		start := recStart + i*recordLen
+		end := start + recordLen
+		if end > len(data) {
+			break
+		}
+		row := data[start:end]
+		if len(row) == 0 || row[0] == '*' {
+			// Deleted record: append a nil placeholder so records[i] stays
+			// aligned with the .shp record of the same index.
+			records = append(records, nil)
+			continue
+		}
+		body := row[1:]
+		rec := make(map[string]string, len(fields))
+		for _, f := range fields {
+			if f.off+f.len > len(body) {
+				continue
+			}
+			rec[f.name] = strings.TrimSpace(decode(body[f.off : f.off+f.len]))
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// dbfDecoder returns a byte-to-string decoder for the given .cpg encoding
+// name. Only the encodings shapefile producers commonly emit are
+// recognized; anything else is treated as already being UTF-8.
+func dbfDecoder(encoding string) func([]byte) string {
+	switch strings.ToUpper(strings.TrimSpace(encoding)) {
+	case "ISO-8859-1", "LATIN1", "8859", "28591":
+		return decodeLatin1
+	case "1252", "WINDOWS-1252", "CP1252":
+		return decodeLatin1 // close enough: only a handful of punctuation code points differ
+	default:
+		return func(b []byte) string { return string(b) }
+	}
+}
+
+// decodeLatin1 maps each byte directly to the Unicode code point of the
+// same value, which is exactly what ISO-8859-1 (and, for our purposes,
+// CP1252) does.
+func decodeLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// buildTestDBF assembles a minimal one-field xBase .dbf buffer with one
+// record per (deleted, value) pair, the same layout parseDBF expects.
+func buildTestDBF(rows []struct {
+	deleted bool
+	value   string
+}) []byte {
+	const fieldLen = 5
+	fieldDesc := make([]byte, 32)
+	copy(fieldDesc, "name")
+	fieldDesc[11] = 'C'
+	fieldDesc[16] = fieldLen
+
+	headerLen := 32 + len(fieldDesc) + 1
+	recordLen := 1 + fieldLen
+
+	header := make([]byte, 32)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(rows)))
+	binary.LittleEndian.PutUint16(header[8:10], uint16(headerLen))
+	binary.LittleEndian.PutUint16(header[10:12], uint16(recordLen))
+
+	data := append(header, fieldDesc...)
+	data = append(data, 0x0D)
+	for _, row := range rows {
+		status := byte(' ')
+		if row.deleted {
+			status = '*'
+		}
+		data = append(data, status)
+		value := make([]byte, fieldLen)
+		copy(value, row.value)
+		for i, b := range value {
+			if b == 0 {
+				value[i] = ' '
+			}
+		}
+		data = append(data, value...)
+	}
+	return data
+}
+
+func TestParseDBFKeepsIndexAlignmentAcrossDeletedRows(t *testing.T) {
+	data := buildTestDBF([]struct {
+		deleted bool
+		value   string
+	}{
+		{false, "aaaa"},
+		{true, "bbbb"},
+		{false, "cccc"},
+	})
+
+	records, err := parseDBF(data, "UTF-8")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3 (one nil placeholder for the deleted row)", len(records))
+	}
+	if records[0]["name"] != "aaaa" {
+		t.Errorf("record 0: got %q, want \"aaaa\"", records[0]["name"])
+	}
+	if records[1] != nil {
+		t.Errorf("record 1 (deleted): got %v, want nil", records[1])
+	}
+	if records[2]["name"] != "cccc" {
+		t.Errorf("record 2: got %q, want \"cccc\"", records[2]["name"])
+	}
+}
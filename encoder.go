@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Encoder writes a rasterized RGBA pixel buffer (straight alpha, 4 bytes
+// per pixel, row-major from the top-left) to w in some image format.
+type Encoder interface {
+	Encode(w io.Writer, pixels []byte, width, height int) error
+}
+
+// encoderFor picks the Encoder matching the output file extension,
+// falling back to the original BMP backend.
+func encoderFor(path string) Encoder {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return pngEncoder{}
+	case ".pdf":
+		return pdfEncoder{dpi: 96}
+	default:
+		return bmpEncoder{}
+	}
+}
+
+// bmpEncoder writes an uncompressed 24-bit BMP, the renderer's original
+// output format. BMP has no alpha channel, so pixels are composited onto
+// a black background first.
+type bmpEncoder struct{}
+
+func (bmpEncoder) Encode(w io.Writer, pixels []byte, width, height int) error {
+	widthInBytes := width * 3
+	paddingSize := (4 - (widthInBytes % 4)) % 4
+	stride := widthInBytes + paddingSize
+
+	if _, err := w.Write(createBitmapFileHeader(height, stride)); err != nil {
+		return err
+	}
+	if _, err := w.Write(createBitmapInfoHeader(height, width)); err != nil {
+		return err
+	}
+
+	padding := make([]byte, paddingSize)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := (y*width + x) * 4
+			a := float64(pixels[i+3]) / 255
+			bgr := [3]byte{
+				byte(float64(pixels[i+2]) * a),
+				byte(float64(pixels[i+1]) * a),
+				byte(float64(pixels[i+0]) * a),
+			}
+			if _, err := w.Write(bgr[:]); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(padding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createBitmapFileHeader(height, stride int) []byte {
+	fileSize := 14 + 40 + stride*height
+	return []byte{
+		'B', 'M',
+		byte(fileSize), byte(fileSize >> 8), byte(fileSize >> 16), byte(fileSize >> 24),
+		0, 0, 0, 0,
+		54, 0, 0, 0,
+	}
+}
+
+func createBitmapInfoHeader(height, width int) []byte {
+	return []byte{
+		40, 0, 0, 0, // Header size
+		byte(width), byte(width >> 8), byte(width >> 16), byte(width >> 24), // Width
+		byte(height), byte(height >> 8), byte(height >> 16), byte(height >> 24), // Height
+		1, 0, // Planes
+		24, 0, // Bits per pixel
+		0, 0, 0, 0, // Compression (no compression)
+		0, 0, 0, 0, // Image size (no compression)
+		0, 0, 0, 0, // X pixels per meter (unspecified)
+		0, 0, 0, 0, // Y pixels per meter (unspecified)
+		0, 0, 0, 0, // Total colors (color table not used)
+		0, 0, 0, 0, // Important colors (generally ignored)
+	}
+}
+
+// pngEncoder writes a straight-alpha PNG via image/png, so the AA band
+// renderTiles produces around every isoline survives into the output.
+type pngEncoder struct{}
+
+func (pngEncoder) Encode(w io.Writer, pixels []byte, width, height int) error {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	copy(img.Pix, pixels)
+	return png.Encode(w, img)
+}
+
+// pdfEncoder rasterizes the scene into a single-page PDF at dpi dots per
+// inch, embedding it as a DeviceRGB Image XObject. PDF has no place for
+// per-pixel alpha in a plain Image XObject, so pixels are composited onto
+// black, same as bmpEncoder.
+type pdfEncoder struct {
+	dpi float64
+}
+
+func (e pdfEncoder) Encode(w io.Writer, pixels []byte, width, height int) error {
+	dpi := e.dpi
+	if dpi <= 0 {
+		dpi = 96
+	}
+
+	rgb := make([]byte, width*height*3)
+	for i, j := 0, 0; i < len(pixels); i, j = i+4, j+3 {
+		a := float64(pixels[i+3]) / 255
+		rgb[j+0] = byte(float64(pixels[i+0]) * a)
+		rgb[j+1] = byte(float64(pixels[i+1]) * a)
+		rgb[j+2] = byte(float64(pixels[i+2]) * a)
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(rgb); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	pageWidthPt := float64(width) / dpi * 72
+	pageHeightPt := float64(height) / dpi * 72
+
+	var buf bytes.Buffer
+	var offsets []int
+	record := func() { offsets = append(offsets, buf.Len()) }
+
+	buf.WriteString("%PDF-1.4\n")
+	record()
+	buf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	record()
+	buf.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	record()
+	fmt.Fprintf(&buf, "3 0 obj\n<< /Type /Page /Parent 2 0 R /Resources << /XObject << /Im0 5 0 R >> >> "+
+		"/MediaBox [0 0 %g %g] /Contents 4 0 R >>\nendobj\n", pageWidthPt, pageHeightPt)
+	record()
+	content := fmt.Sprintf("q %g 0 0 %g 0 0 cm /Im0 Do Q", pageWidthPt, pageHeightPt)
+	fmt.Fprintf(&buf, "4 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(content), content)
+	record()
+	fmt.Fprintf(&buf, "5 0 obj\n<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB "+
+		"/BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n", width, height, compressed.Len())
+	buf.Write(compressed.Bytes())
+	buf.WriteString("\nendstream\nendobj\n")
+
+	xrefStart := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF", len(offsets)+1, xrefStart)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func solidPixels(width, height int, r, g, b, a byte) []byte {
+	pixels := make([]byte, width*height*4)
+	for i := 0; i < len(pixels); i += 4 {
+		pixels[i], pixels[i+1], pixels[i+2], pixels[i+3] = r, g, b, a
+	}
+	return pixels
+}
+
+func TestPNGEncoderRoundTrip(t *testing.T) {
+	const width, height = 4, 3
+	pixels := solidPixels(width, height, 10, 20, 30, 255)
+
+	var buf bytes.Buffer
+	if err := (pngEncoder{}).Encode(&buf, pixels, width, height); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("png.Decode: %v", err)
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() != width || bounds.Dy() != height {
+		t.Fatalf("got bounds %v, want %dx%d", bounds, width, height)
+	}
+	r, g, b, a := img.At(0, 0).RGBA()
+	if r>>8 != 10 || g>>8 != 20 || b>>8 != 30 || a>>8 != 255 {
+		t.Errorf("got pixel (%d,%d,%d,%d), want (10,20,30,255)", r>>8, g>>8, b>>8, a>>8)
+	}
+}
+
+func TestPDFEncoderXrefOffsetsMatchObjects(t *testing.T) {
+	const width, height = 2, 2
+	pixels := solidPixels(width, height, 255, 0, 0, 255)
+
+	var buf bytes.Buffer
+	if err := (pdfEncoder{dpi: 96}).Encode(&buf, pixels, width, height); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	data := buf.Bytes()
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) {
+		t.Fatalf("missing PDF header")
+	}
+
+	xrefIdx := bytes.LastIndex(data, []byte("\nxref\n"))
+	if xrefIdx < 0 {
+		t.Fatalf("missing xref table")
+	}
+	startxrefMatch := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	if startxrefMatch == nil {
+		t.Fatalf("missing startxref")
+	}
+	startxref, err := strconv.Atoi(string(startxrefMatch[1]))
+	if err != nil {
+		t.Fatalf("bad startxref: %v", err)
+	}
+	if startxref != xrefIdx+1 {
+		t.Errorf("startxref points to %d, want %d (the actual \"xref\" keyword)", startxref, xrefIdx+1)
+	}
+
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data, -1)
+	if len(offsets) != 5 {
+		t.Fatalf("got %d object offsets, want 5 (one per PDF object)", len(offsets))
+	}
+	for i, m := range offsets {
+		off, err := strconv.Atoi(string(m[1]))
+		if err != nil {
+			t.Fatalf("bad offset: %v", err)
+		}
+		want := []byte(strconv.Itoa(i+1) + " 0 obj")
+		if off+len(want) > len(data) || !bytes.Equal(data[off:off+len(want)], want) {
+			t.Errorf("xref offset %d doesn't point at %q", off, want)
+		}
+	}
+}
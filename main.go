@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -21,6 +22,16 @@ const (
 const (
 	PointType = iota
 	SegmentType
+	CircleType
+	BoxType
+	TriangleType
+	ArcType
+	QuadraticBezierType
+	PolygonType
+	UnionType
+	SubtractType
+	IntersectType
+	SmoothUnionType
 )
 
 // Error codes
@@ -42,7 +53,66 @@ type Geom struct {
 	geomType int
 	point    Point
 	segment  Segment
+	circle   Circle
+	box      Box
+	triangle Triangle
+	arc      Arc
+	bezier   QuadraticBezier
+	polygon  Polygon
 	roundR   float64
+	attrs    map[string]string
+	style    Style
+
+	// children and k are only set for the CSG combinator types
+	// (UnionType, SubtractType, IntersectType, SmoothUnionType), which
+	// combine two child Geoms instead of carrying their own shape data.
+	children []*Geom
+	k        float64
+}
+
+// GeomSource produces the geometries that should be rendered, regardless
+// of the file format they were read from.
+type GeomSource interface {
+	Load() ([]Geom, error)
+}
+
+// wktSource reads one WKT literal per line, as the renderer always has.
+type wktSource struct {
+	path string
+}
+
+func (s wktSource) Load() ([]Geom, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	geoms := make([]Geom, 0, MaxGeoms)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		geo, err := parseLine(line)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		geoms = append(geoms, geo)
+		if len(geoms) >= MaxGeoms {
+			break
+		}
+	}
+	return geoms, scanner.Err()
+}
+
+// geomSourceFor picks the GeomSource matching the input file extension.
+func geomSourceFor(path string) GeomSource {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".shp", ".zip":
+		return shapefileSource{path: path}
+	default:
+		return wktSource{path: path}
+	}
 }
 
 func parseNumber(str string, cursor *int) (float64, error) {
@@ -117,99 +187,22 @@ func parseRound(line string, cursor *int) (Geom, error) {
 }
 
 func parseLine(line string) (Geom, error) {
-	var geo Geom
 	cursor := 0
-	switch {
-	case strings.HasPrefix(line, "POINT"):
-		point, err := parsePoint(line, &cursor)
-		if err != nil {
-			return geo, err
-		}
-		geo.geomType = PointType
-		geo.point = point
-	case strings.HasPrefix(line, "SEGMENT"):
-		segment, err := parseSegment(line, &cursor)
-		if err != nil {
-			return geo, err
-		}
-		geo.geomType = SegmentType
-		geo.segment = segment
-	case strings.HasPrefix(line, "ROUND"):
-		round, err := parseRound(line, &cursor)
-		if err != nil {
-			return geo, err
-		}
-		geo = round
-	default:
-		return geo, ErrUnsupportedWKT
-	}
-	return geo, nil
-}
-
-func createBitmapFileHeader(height, stride int) []byte {
-	fileSize := 14 + 40 + stride*height
-	return []byte{
-		'B', 'M',
-		byte(fileSize), byte(fileSize >> 8), byte(fileSize >> 16), byte(fileSize >> 24),
-		0, 0, 0, 0,
-		54, 0, 0, 0,
-	}
-}
-
-func createBitmapInfoHeader(height, width int) []byte {
-	return []byte{
-		40, 0, 0, 0, // Header size
-		byte(width), byte(width >> 8), byte(width >> 16), byte(width >> 24), // Width
-		byte(height), byte(height >> 8), byte(height >> 16), byte(height >> 24), // Height
-		1, 0, // Planes
-		24, 0, // Bits per pixel
-		0, 0, 0, 0, // Compression (no compression)
-		0, 0, 0, 0, // Image size (no compression)
-		0, 0, 0, 0, // X pixels per meter (unspecified)
-		0, 0, 0, 0, // Y pixels per meter (unspecified)
-		0, 0, 0, 0, // Total colors (color table not used)
-		0, 0, 0, 0, // Important colors (generally ignored)
-	}
-}
-
-func writeBitmap(geoms []Geom, size int, imageFileName string) error {
-	widthInBytes := CanvasWidth * 3
-	paddingSize := (4 - (widthInBytes % 4)) % 4
-	stride := widthInBytes + paddingSize
-
-	file, err := os.Create(imageFileName)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	fileHeader := createBitmapFileHeader(CanvasHeight, stride)
-	_, err = file.Write(fileHeader)
+	geo, err := parseExpr(line, &cursor)
 	if err != nil {
-		return err
+		return Geom{}, err
 	}
-
-	infoHeader := createBitmapInfoHeader(CanvasHeight, CanvasWidth)
-	_, err = file.Write(infoHeader)
-	if err != nil {
-		return err
+	if cursor > len(line) {
+		return Geom{}, ErrUnsupportedWKT
 	}
-
-	for y := 0; y < CanvasHeight; y++ {
-		for x := 0; x < CanvasWidth; x++ {
-			pixel := [3]byte{0, 0, 0}
-			sdRender(geoms, float64(x), float64(y), &pixel)
-			_, err := file.Write(pixel[:])
-			if err != nil {
-				return err
-			}
-		}
-		_, err := file.Write(make([]byte, paddingSize))
+	if rest := strings.TrimSpace(line[cursor:]); rest != "" {
+		style, err := parseStyle(rest)
 		if err != nil {
-			return err
+			return Geom{}, err
 		}
+		geo.style = style
 	}
-	return nil
+	return *geo, nil
 }
 
 /* SDF */
@@ -272,56 +265,85 @@ func sdSegment(p, a, b Point) float64 {
 	return length(sub(pa, mul(ba, h)))
 }
 
-// sdRender function
-func sdRender(geoms []Geom, x, y float64, pixel *[3]byte) {
+// sdRender evaluates the geoms named by indices at (x, y) and returns the
+// winning one's color and alpha. The winning geom's own STYLE wins if it
+// has one; otherwise the deterministic per-index palette this renderer
+// has always used applies, antialiased by the usual distance-based alpha.
+// indices lets callers cull to only the geoms that can possibly matter
+// for this pixel (see renderTiles).
+func sdRender(geoms []Geom, indices []int, x, y float64) (color [3]byte, alpha float64) {
 	d := length(Point{CanvasWidth, CanvasHeight})
 	p := Point{x, y}
-	for i := 0; i < len(geoms); i++ {
-		switch geoms[i].geomType {
-		case PointType:
-			d = min(d, opRound(sdPoint(p, geoms[i].point), geoms[i].roundR))
-		case SegmentType:
-			d = min(d, opRound(sdSegment(p, geoms[i].segment.a, geoms[i].segment.b), geoms[i].roundR))
-		}
-
-		if d < 0 {
-			pixel[0] = byte((i * 2) % 255)
-			pixel[1] = byte((100 + i) % 255)
-			pixel[2] = byte((50 / (i + 1)) % 255)
-			break
+	winner := -1
+	for _, i := range indices {
+		if gd := sdGeom(&geoms[i], p); gd < d {
+			d = gd
+			winner = i
 		}
 	}
+	if winner < 0 {
+		return color, 0
+	}
+	if style := geoms[winner].style; style.hasFill || style.hasStroke {
+		return style.render(d)
+	}
+	color[0] = byte((winner * 2) % 255)
+	color[1] = byte((100 + winner) % 255)
+	color[2] = byte((50 / (winner + 1)) % 255)
+	return color, clamp(0.5-d, 0, 1)
 }
 
 /* === */
 
 func main() {
-	geoms := make([]Geom, 0, MaxGeoms)
-
-	file, err := os.Open("segments.wkt")
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	inputFile := "segments.wkt"
+	outputFile := "canvas.bmp"
+	if len(os.Args) > 1 {
+		inputFile = os.Args[1]
+	}
+	if len(os.Args) > 2 {
+		outputFile = os.Args[2]
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		geo, err := parseLine(line)
+	if ext := strings.ToLower(filepath.Ext(outputFile)); ext == ".mp4" || ext == ".gif" {
+		scene, err := loadScene(inputFile)
 		if err != nil {
 			fmt.Println(err)
-			continue
+			os.Exit(1)
 		}
-		geoms = append(geoms, geo)
-		if len(geoms) >= MaxGeoms {
-			break
+		nFrames := 60
+		if len(os.Args) > 3 {
+			if n, err := strconv.Atoi(os.Args[3]); err == nil {
+				nFrames = n
+			}
 		}
+		if err := RenderSequence(scene, nFrames, outputFile, os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	geoms, err := geomSourceFor(inputFile).Load()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(geoms) > MaxGeoms {
+		geoms = geoms[:MaxGeoms]
 	}
 
-	err = writeBitmap(geoms, len(geoms), "canvas.bmp")
+	pixels := renderTiles(geoms, CanvasWidth, CanvasHeight, RenderOptions{})
+
+	file, err := os.Create(outputFile)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	defer file.Close()
+
+	if err := encoderFor(outputFile).Encode(file, pixels, CanvasWidth, CanvasHeight); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
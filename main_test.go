@@ -0,0 +1,9 @@
+package main
+
+import "testing"
+
+func TestParseLineTruncatedPoint(t *testing.T) {
+	if _, err := parseLine("POINT(0.5 0.5"); err == nil {
+		t.Errorf("expected an error for a POINT missing its closing paren, got nil")
+	}
+}
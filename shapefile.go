@@ -0,0 +1,327 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Shapefile shape types, as found in the .shp record header.
+// The Z and M variants carry extra coordinate data we don't use, but the
+// X/Y part of the record is laid out the same way.
+const (
+	shpNull        = 0
+	shpPoint       = 1
+	shpPolyLine    = 3
+	shpPolygon     = 5
+	shpMultiPoint  = 8
+	shpPointZ      = 11
+	shpPolyLineZ   = 13
+	shpPolygonZ    = 15
+	shpMultiPointZ = 18
+	shpPointM      = 21
+	shpPolyLineM   = 23
+	shpPolygonM    = 25
+	shpMultiPointM = 28
+)
+
+var ErrUnsupportedShapeType = errors.New("unsupported shapefile shape type")
+
+// shapefileSource loads geometries (and their DBF attributes) from an ESRI
+// Shapefile bundle: a .shp with the geometry, a sibling .dbf with the
+// per-record attributes, and an optional .cpg giving the .dbf's encoding.
+// path may point directly at the .shp, or at a .zip containing the bundle.
+type shapefileSource struct {
+	path string
+}
+
+func (s shapefileSource) Load() ([]Geom, error) {
+	shp, dbfData, cpgData, err := readShapefileParts(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer shp.Close()
+
+	geoms, recIndices, err := parseSHP(bufio.NewReader(shp))
+	if err != nil {
+		return nil, err
+	}
+
+	if dbfData != nil {
+		encoding := "UTF-8"
+		if cpgData != nil {
+			encoding = strings.TrimSpace(string(cpgData))
+		}
+		records, err := parseDBF(dbfData, encoding)
+		if err != nil {
+			return nil, err
+		}
+		applyAttrs(geoms, recIndices, records)
+	}
+
+	return geoms, nil
+}
+
+// readShapefileParts opens the .shp member of the bundle for streaming
+// (the part that can run into the hundreds of megabytes for a real-world
+// shapefile) and returns the .dbf and .cpg members in full (the per-record
+// attribute table and its tiny encoding hint, not worth streaming). It
+// reads either plain sibling files or entries inside a zip archive
+// depending on path's extension.
+func readShapefileParts(path string) (shp io.ReadCloser, dbf, cpg []byte, err error) {
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		return readShapefilePartsFromZip(path)
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	if shp, err = os.Open(base + ".shp"); err != nil {
+		return nil, nil, nil, err
+	}
+	dbf, _ = os.ReadFile(base + ".dbf")
+	cpg, _ = os.ReadFile(base + ".cpg")
+	return shp, dbf, cpg, nil
+}
+
+// zipEntryStream closes both the opened zip entry and the archive itself,
+// so readShapefilePartsFromZip's caller only has to Close() the one value
+// it got back.
+type zipEntryStream struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (z zipEntryStream) Close() error {
+	err := z.ReadCloser.Close()
+	if cerr := z.archive.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func readShapefilePartsFromZip(path string) (shp io.ReadCloser, dbf, cpg []byte, err error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var shpFile *zip.File
+	for _, f := range r.File {
+		switch strings.ToLower(filepath.Ext(f.Name)) {
+		case ".shp":
+			shpFile = f
+		case ".dbf":
+			if dbf, err = readZipFile(f); err != nil {
+				r.Close()
+				return nil, nil, nil, err
+			}
+		case ".cpg":
+			if cpg, err = readZipFile(f); err != nil {
+				r.Close()
+				return nil, nil, nil, err
+			}
+		}
+	}
+	if shpFile == nil {
+		r.Close()
+		return nil, nil, nil, errors.New("zip archive has no .shp member")
+	}
+	rc, err := shpFile.Open()
+	if err != nil {
+		r.Close()
+		return nil, nil, nil, err
+	}
+	return zipEntryStream{rc, r}, dbf, cpg, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// parseSHP streams the .shp records one at a time off r, dispatching on
+// shape type and normalizing every coordinate into the canvas using the
+// file's own bounding box (shapefiles carry arbitrary real-world units,
+// unlike the [0,1]-normalized WKT input). It also returns, for every Geom
+// produced, the index of the .shp/.dbf record it came from, since one
+// record can expand into several Geoms (one per polygon/polyline edge).
+func parseSHP(r io.Reader) ([]Geom, []int, error) {
+	header := make([]byte, 100)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, errors.New("shapefile header truncated")
+	}
+
+	xmin := math.Float64frombits(binary.LittleEndian.Uint64(header[36:44]))
+	ymin := math.Float64frombits(binary.LittleEndian.Uint64(header[44:52]))
+	xmax := math.Float64frombits(binary.LittleEndian.Uint64(header[52:60]))
+	ymax := math.Float64frombits(binary.LittleEndian.Uint64(header[60:68]))
+	toCanvas := func(x, y float64) Point {
+		u := 0.5
+		v := 0.5
+		if xmax > xmin {
+			u = (x - xmin) / (xmax - xmin)
+		}
+		if ymax > ymin {
+			v = (y - ymin) / (ymax - ymin)
+		}
+		return Point{u * CanvasWidth, (1 - v) * CanvasHeight}
+	}
+
+	geoms := make([]Geom, 0, MaxGeoms)
+	recIndices := make([]int, 0, MaxGeoms)
+	recHeader := make([]byte, 8)
+	for recNum := 0; len(geoms) < MaxGeoms; recNum++ {
+		if _, err := io.ReadFull(r, recHeader); err != nil {
+			break // EOF (or a truncated trailing header): no more records
+		}
+		contentLen := int(binary.BigEndian.Uint32(recHeader[4:8])) * 2
+		record := make([]byte, contentLen)
+		if _, err := io.ReadFull(r, record); err != nil {
+			break
+		}
+		recGeoms, err := parseSHPRecord(record, toCanvas)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, g := range recGeoms {
+			geoms = append(geoms, g)
+			recIndices = append(recIndices, recNum)
+		}
+	}
+	return geoms, recIndices, nil
+}
+
+func parseSHPRecord(record []byte, toCanvas func(x, y float64) Point) ([]Geom, error) {
+	if len(record) < 4 {
+		return nil, nil
+	}
+	shapeType := int(binary.LittleEndian.Uint32(record[0:4]))
+	body := record[4:]
+
+	switch shapeType {
+	case shpNull:
+		return nil, nil
+	case shpPoint, shpPointZ, shpPointM:
+		if len(body) < 16 {
+			return nil, nil
+		}
+		x := math.Float64frombits(binary.LittleEndian.Uint64(body[0:8]))
+		y := math.Float64frombits(binary.LittleEndian.Uint64(body[8:16]))
+		return []Geom{{geomType: PointType, point: toCanvas(x, y)}}, nil
+	case shpMultiPoint, shpMultiPointZ, shpMultiPointM:
+		if len(body) < 36 {
+			return nil, nil
+		}
+		numPoints := int(binary.LittleEndian.Uint32(body[32:36]))
+		points, _, err := readSHPPoints(body[36:], numPoints, toCanvas)
+		if err != nil {
+			return nil, err
+		}
+		geoms := make([]Geom, len(points))
+		for i, p := range points {
+			geoms[i] = Geom{geomType: PointType, point: p}
+		}
+		return geoms, nil
+	case shpPolyLine, shpPolyLineZ, shpPolyLineM, shpPolygon, shpPolygonZ, shpPolygonM:
+		return parseSHPMultiPart(body, toCanvas, shapeType == shpPolygon || shapeType == shpPolygonZ || shapeType == shpPolygonM)
+	default:
+		return nil, ErrUnsupportedShapeType
+	}
+}
+
+// readSHPPoints reads n consecutive (x,y) float64 pairs starting at data[0].
+func readSHPPoints(data []byte, n int, toCanvas func(x, y float64) Point) ([]Point, int, error) {
+	points := make([]Point, n)
+	cursor := 0
+	for i := 0; i < n; i++ {
+		if cursor+16 > len(data) {
+			return nil, cursor, errors.New("shapefile point data truncated")
+		}
+		x := math.Float64frombits(binary.LittleEndian.Uint64(data[cursor : cursor+8]))
+		y := math.Float64frombits(binary.LittleEndian.Uint64(data[cursor+8 : cursor+16]))
+		points[i] = toCanvas(x, y)
+		cursor += 16
+	}
+	return points, cursor, nil
+}
+
+// parseSHPMultiPart turns a PolyLine/Polygon record into one SegmentType
+// Geom per edge, one ring/part at a time; polygon rings are closed.
+func parseSHPMultiPart(body []byte, toCanvas func(x, y float64) Point, closeRing bool) ([]Geom, error) {
+	if len(body) < 40 {
+		return nil, errors.New("shapefile part data truncated")
+	}
+	numParts := int(binary.LittleEndian.Uint32(body[32:36]))
+	numPoints := int(binary.LittleEndian.Uint32(body[36:40]))
+
+	partsOffset := 40
+	if numParts < 0 || numParts > (len(body)-partsOffset)/4 {
+		return nil, errors.New("shapefile part data truncated")
+	}
+	parts := make([]int, numParts)
+	for i := 0; i < numParts; i++ {
+		parts[i] = int(binary.LittleEndian.Uint32(body[partsOffset+i*4 : partsOffset+i*4+4]))
+	}
+
+	pointsOffset := partsOffset + numParts*4
+	if numPoints < 0 || numPoints > (len(body)-pointsOffset)/16 {
+		return nil, errors.New("shapefile part data truncated")
+	}
+	points, _, err := readSHPPoints(body[pointsOffset:], numPoints, toCanvas)
+	if err != nil {
+		return nil, err
+	}
+
+	var geoms []Geom
+	for i, start := range parts {
+		end := numPoints
+		if i+1 < len(parts) {
+			end = parts[i+1]
+		}
+		if start < 0 || start > end || end > len(points) {
+			return nil, errors.New("shapefile part data truncated")
+		}
+		ring := points[start:end]
+		for j := 0; j+1 < len(ring); j++ {
+			geoms = append(geoms, Geom{geomType: SegmentType, segment: Segment{a: ring[j], b: ring[j+1]}})
+		}
+		if closeRing && len(ring) > 1 {
+			geoms = append(geoms, Geom{geomType: SegmentType, segment: Segment{a: ring[len(ring)-1], b: ring[0]}})
+		}
+	}
+	return geoms, nil
+}
+
+// applyAttrs attaches each DBF record's fields to the geoms produced from
+// the matching .shp record (recIndices[i] names which record geoms[i]
+// came from). roundR is additionally picked up from a "roundr" field if
+// present, so attributes can drive rendering and not just display.
+func applyAttrs(geoms []Geom, recIndices []int, records []map[string]string) {
+	for i, recIdx := range recIndices {
+		if recIdx < 0 || recIdx >= len(records) {
+			continue
+		}
+		attrs := records[recIdx]
+		geoms[i].attrs = attrs
+		if r, ok := attrs["roundr"]; ok {
+			if v, err := strconv.ParseFloat(r, 64); err == nil {
+				geoms[i].roundR = v * CanvasDiag
+			}
+		}
+		if fill, ok := attrs["fill"]; ok {
+			if c, err := parseColor(fill); err == nil {
+				geoms[i].style.fill, geoms[i].style.hasFill = c, true
+			}
+		}
+	}
+}
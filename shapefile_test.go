@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// buildTestSHP assembles a minimal .shp buffer holding one shpPoint record
+// at (x, y), with a bounding box wide enough that toCanvas doesn't degenerate.
+func buildTestSHP(x, y float64) []byte {
+	header := make([]byte, 100)
+	binary.LittleEndian.PutUint64(header[36:44], math.Float64bits(0))
+	binary.LittleEndian.PutUint64(header[44:52], math.Float64bits(0))
+	binary.LittleEndian.PutUint64(header[52:60], math.Float64bits(10))
+	binary.LittleEndian.PutUint64(header[60:68], math.Float64bits(10))
+
+	body := make([]byte, 20)
+	binary.LittleEndian.PutUint32(body[0:4], shpPoint)
+	binary.LittleEndian.PutUint64(body[4:12], math.Float64bits(x))
+	binary.LittleEndian.PutUint64(body[12:20], math.Float64bits(y))
+
+	recHeader := make([]byte, 8)
+	binary.BigEndian.PutUint32(recHeader[4:8], uint32(len(body)/2))
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.Write(recHeader)
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+func TestParseSHPStreamsPointRecord(t *testing.T) {
+	data := buildTestSHP(5, 5)
+	geoms, recIndices, err := parseSHP(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(geoms) != 1 || len(recIndices) != 1 {
+		t.Fatalf("got %d geoms / %d recIndices, want 1 / 1", len(geoms), len(recIndices))
+	}
+	if geoms[0].geomType != PointType {
+		t.Errorf("got geomType %v, want PointType", geoms[0].geomType)
+	}
+	if recIndices[0] != 0 {
+		t.Errorf("got recIndex %d, want 0", recIndices[0])
+	}
+}
+
+func TestParseSHPTruncatedHeader(t *testing.T) {
+	if _, _, err := parseSHP(bytes.NewReader(make([]byte, 10))); err == nil {
+		t.Errorf("expected an error for a truncated header, got nil")
+	}
+}
+
+// buildTestPolygonBody assembles a shpPolygon record body with a single
+// ring of the given points, the layout parseSHPMultiPart expects after the
+// leading shape-type word.
+func buildTestPolygonBody(points []Point) []byte {
+	body := make([]byte, 44+len(points)*16)
+	binary.LittleEndian.PutUint32(body[32:36], 1) // numParts
+	binary.LittleEndian.PutUint32(body[36:40], uint32(len(points)))
+	binary.LittleEndian.PutUint32(body[40:44], 0) // parts[0] = 0
+	for i, p := range points {
+		off := 44 + i*16
+		binary.LittleEndian.PutUint64(body[off:off+8], math.Float64bits(p.x))
+		binary.LittleEndian.PutUint64(body[off+8:off+16], math.Float64bits(p.y))
+	}
+	return body
+}
+
+func identity(x, y float64) Point { return Point{x, y} }
+
+func TestParseSHPMultiPartValidPolygon(t *testing.T) {
+	body := buildTestPolygonBody([]Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}})
+	geoms, err := parseSHPMultiPart(body, identity, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(geoms) != 4 { // 3 edges + 1 closing segment
+		t.Errorf("got %d geoms, want 4", len(geoms))
+	}
+}
+
+func TestParseSHPMultiPartRejectsShortBody(t *testing.T) {
+	// len(body) == 38: long enough to hold numParts at body[32:36] but
+	// still short of the 40 bytes needed to read numPoints at body[36:40].
+	if _, err := parseSHPMultiPart(make([]byte, 38), identity, false); err == nil {
+		t.Errorf("expected an error for a body too short to hold numPoints, got nil")
+	}
+}
+
+func TestParseSHPMultiPartRejectsOversizedNumParts(t *testing.T) {
+	body := make([]byte, 40)
+	binary.LittleEndian.PutUint32(body[32:36], 5_000_000) // numParts, far beyond len(body)
+	if _, err := parseSHPMultiPart(body, identity, false); err == nil {
+		t.Errorf("expected an error for an oversized numParts, got nil")
+	}
+}
+
+func TestParseSHPMultiPartRejectsOversizedNumPoints(t *testing.T) {
+	body := make([]byte, 44) // room for 1 part, no room for any points
+	binary.LittleEndian.PutUint32(body[32:36], 1)
+	binary.LittleEndian.PutUint32(body[36:40], 5_000_000) // numPoints, far beyond len(body)
+	if _, err := parseSHPMultiPart(body, identity, false); err == nil {
+		t.Errorf("expected an error for an oversized numPoints, got nil")
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidStyle = errors.New("invalid style")
+
+// Style is a geom's optional STYLE(...) annotation: a fill color, a
+// stroke color, and the stroke width, in canvas pixels, straddling the
+// geom's isoline.
+type Style struct {
+	fill, stroke       color.RGBA
+	hasFill, hasStroke bool
+	width              float64
+}
+
+// parseStyle parses the STYLE(...) annotation that may follow a
+// geometry expression on a WKT line, e.g.
+// STYLE(fill=#ff8800, stroke=rgba(0,0,0,128), width=2.0)
+func parseStyle(s string) (Style, error) {
+	style := Style{width: 1}
+	if !strings.HasPrefix(s, "STYLE(") || !strings.HasSuffix(s, ")") {
+		return style, ErrInvalidStyle
+	}
+	body := s[len("STYLE(") : len(s)-1]
+
+	for _, part := range splitTopLevel(body, ',') {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return style, ErrInvalidStyle
+		}
+		key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		switch key {
+		case "fill":
+			c, err := parseColor(val)
+			if err != nil {
+				return style, err
+			}
+			style.fill, style.hasFill = c, true
+		case "stroke":
+			c, err := parseColor(val)
+			if err != nil {
+				return style, err
+			}
+			style.stroke, style.hasStroke = c, true
+		case "width":
+			w, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return style, err
+			}
+			style.width = w
+		default:
+			return style, ErrInvalidStyle
+		}
+	}
+	return style, nil
+}
+
+// splitTopLevel splits s on sep, except for seps nested inside
+// parentheses, so e.g. "fill=#fff, stroke=rgba(0,0,0,128)" splits into
+// two parts instead of five.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+	return parts
+}
+
+// parseColor accepts the #RRGGBB, #RRGGBBAA, rgb(r,g,b) and
+// rgba(r,g,b,a) color formats.
+func parseColor(s string) (color.RGBA, error) {
+	switch {
+	case strings.HasPrefix(s, "#"):
+		return parseHexColor(s)
+	case strings.HasPrefix(s, "rgba("):
+		return parseFuncColor(s, "rgba(", 4)
+	case strings.HasPrefix(s, "rgb("):
+		return parseFuncColor(s, "rgb(", 3)
+	default:
+		return color.RGBA{}, ErrInvalidStyle
+	}
+}
+
+func parseHexColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 && len(s) != 8 {
+		return color.RGBA{}, ErrInvalidStyle
+	}
+	v, err := strconv.ParseUint(s[:6], 16, 32)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	c := color.RGBA{R: byte(v >> 16), G: byte(v >> 8), B: byte(v), A: 255}
+	if len(s) == 8 {
+		a, err := strconv.ParseUint(s[6:8], 16, 8)
+		if err != nil {
+			return color.RGBA{}, err
+		}
+		c.A = byte(a)
+	}
+	return c, nil
+}
+
+func parseFuncColor(s, prefix string, numComponents int) (color.RGBA, error) {
+	if !strings.HasSuffix(s, ")") {
+		return color.RGBA{}, ErrInvalidStyle
+	}
+	body := s[len(prefix) : len(s)-1]
+	parts := strings.Split(body, ",")
+	if len(parts) != numComponents {
+		return color.RGBA{}, ErrInvalidStyle
+	}
+	vals := make([]float64, numComponents)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return color.RGBA{}, err
+		}
+		if v < 0 || v > 255 {
+			return color.RGBA{}, ErrInvalidStyle
+		}
+		vals[i] = v
+	}
+	c := color.RGBA{R: byte(vals[0]), G: byte(vals[1]), B: byte(vals[2]), A: 255}
+	if numComponents == 4 {
+		c.A = byte(vals[3])
+	}
+	return c, nil
+}
+
+// render picks between fill (d < -width/2), stroke (|d| < width/2) and
+// background at distance d from the geom's isoline, antialiasing a
+// one-pixel band at each boundary and blending by the color's own alpha.
+func (s Style) render(d float64) (rgb [3]byte, alpha float64) {
+	half := s.width / 2
+	switch {
+	case s.hasFill && d < -half+0.5:
+		edge := clamp(0.5-(d+half), 0, 1)
+		return colorRGB(s.fill), edge * colorAlpha(s.fill)
+	case s.hasStroke && abs(d)-half < 0.5:
+		edge := clamp(0.5-(abs(d)-half), 0, 1)
+		return colorRGB(s.stroke), edge * colorAlpha(s.stroke)
+	default:
+		return rgb, 0
+	}
+}
+
+func colorRGB(c color.RGBA) [3]byte {
+	return [3]byte{c.R, c.G, c.B}
+}
+
+func colorAlpha(c color.RGBA) float64 {
+	return float64(c.A) / 255
+}
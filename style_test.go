@@ -0,0 +1,98 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseColorHex(t *testing.T) {
+	c, err := parseColor("#ff8800")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (color.RGBA{0xff, 0x88, 0x00, 0xff}); c != want {
+		t.Errorf("got %v, want %v", c, want)
+	}
+}
+
+func TestParseColorHexWithAlpha(t *testing.T) {
+	c, err := parseColor("#ff880080")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (color.RGBA{0xff, 0x88, 0x00, 0x80}); c != want {
+		t.Errorf("got %v, want %v", c, want)
+	}
+}
+
+func TestParseColorRGB(t *testing.T) {
+	c, err := parseColor("rgb(255, 136, 0)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (color.RGBA{255, 136, 0, 255}); c != want {
+		t.Errorf("got %v, want %v", c, want)
+	}
+}
+
+func TestParseColorRGBA(t *testing.T) {
+	c, err := parseColor("rgba(0, 0, 0, 128)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (color.RGBA{0, 0, 0, 128}); c != want {
+		t.Errorf("got %v, want %v", c, want)
+	}
+}
+
+func TestParseColorRGBRejectsOutOfRangeComponent(t *testing.T) {
+	if _, err := parseColor("rgb(300, 0, 0)"); err == nil {
+		t.Errorf("expected an error for a component > 255, got nil")
+	}
+	if _, err := parseColor("rgb(-1, 0, 0)"); err == nil {
+		t.Errorf("expected an error for a negative component, got nil")
+	}
+}
+
+func TestParseColorMalformed(t *testing.T) {
+	cases := []string{
+		"",
+		"#ff88",       // wrong hex length
+		"rgb(1, 2)",   // missing component
+		"rgba(1,2,3)", // missing alpha
+		"plaid",
+	}
+	for _, s := range cases {
+		if _, err := parseColor(s); err == nil {
+			t.Errorf("parseColor(%q): expected an error, got nil", s)
+		}
+	}
+}
+
+func TestParseStyle(t *testing.T) {
+	style, err := parseStyle("STYLE(fill=#ff8800, stroke=rgba(0,0,0,128), width=2.0)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !style.hasFill || !style.hasStroke {
+		t.Fatalf("expected both fill and stroke to be set, got %+v", style)
+	}
+	if style.width != 2.0 {
+		t.Errorf("got width %v, want 2.0", style.width)
+	}
+}
+
+func TestParseStyleMalformed(t *testing.T) {
+	cases := []string{
+		"STYLE(fill=#ff8800",       // missing closing paren
+		"fill=#ff8800)",            // missing STYLE( prefix
+		"STYLE(fill)",              // no "=value"
+		"STYLE(opacity=0.5)",       // unsupported key
+		"STYLE(fill=notacolor)",
+	}
+	for _, s := range cases {
+		if _, err := parseStyle(s); err == nil {
+			t.Errorf("parseStyle(%q): expected an error, got nil", s)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"math"
+	"runtime"
+	"sync"
+)
+
+// RenderOptions controls how renderTiles parallelizes rasterization.
+// The zero value picks sensible defaults (see renderTiles).
+type RenderOptions struct {
+	Tile    int // tile edge length in pixels
+	Workers int // number of rendering goroutines
+}
+
+const defaultTileSize = 32
+
+// AABB is an axis-aligned bounding box in canvas pixel coordinates.
+type AABB struct {
+	minX, minY, maxX, maxY float64
+}
+
+func pointAABB(p Point, r float64) AABB {
+	return AABB{p.x - r, p.y - r, p.x + r, p.y + r}
+}
+
+func (a AABB) union(b AABB) AABB {
+	return AABB{min(a.minX, b.minX), min(a.minY, b.minY), max(a.maxX, b.maxX), max(a.maxY, b.maxY)}
+}
+
+// geomAABB computes a conservative bounding box for g, expanded by its
+// roundR the same way opRound expands the shape itself. CSG combinators
+// use the union of both children's boxes; that's exact for opUnion, and a
+// safe (if loose) over-approximation for subtract and intersect.
+// opSmoothUnion can reach up to k/4 past either child, so its box is
+// grown by k/4 on every side.
+func geomAABB(g *Geom) AABB {
+	switch g.geomType {
+	case PointType:
+		return pointAABB(g.point, g.roundR)
+	case SegmentType:
+		return pointAABB(g.segment.a, g.roundR).union(pointAABB(g.segment.b, g.roundR))
+	case CircleType:
+		return pointAABB(g.circle.center, g.circle.r+g.roundR)
+	case BoxType:
+		c, h, r := g.box.center, g.box.half, g.roundR
+		return AABB{c.x - h.x - r, c.y - h.y - r, c.x + h.x + r, c.y + h.y + r}
+	case TriangleType:
+		box := pointAABB(g.triangle.a, g.roundR)
+		box = box.union(pointAABB(g.triangle.b, g.roundR))
+		box = box.union(pointAABB(g.triangle.c, g.roundR))
+		return box
+	case ArcType:
+		return pointAABB(g.arc.center, g.arc.r+g.roundR)
+	case QuadraticBezierType:
+		// The curve lies within the convex hull of its control points.
+		box := pointAABB(g.bezier.a, g.roundR)
+		box = box.union(pointAABB(g.bezier.b, g.roundR))
+		box = box.union(pointAABB(g.bezier.c, g.roundR))
+		return box
+	case PolygonType:
+		box := pointAABB(g.polygon.points[0], g.roundR)
+		for _, p := range g.polygon.points[1:] {
+			box = box.union(pointAABB(p, g.roundR))
+		}
+		return box
+	case UnionType, SubtractType, IntersectType:
+		return geomAABB(g.children[0]).union(geomAABB(g.children[1]))
+	case SmoothUnionType:
+		box := geomAABB(g.children[0]).union(geomAABB(g.children[1]))
+		r := g.k / 4
+		return AABB{box.minX - r, box.minY - r, box.maxX + r, box.maxY + r}
+	default:
+		return AABB{}
+	}
+}
+
+// tileGrid is a uniform acceleration structure over geometry AABBs: each
+// cell lists the indices of the geoms whose (expanded) bounding box
+// overlaps it, so a tile's candidate list is a single lookup instead of
+// a scan over every geom in the scene.
+type tileGrid struct {
+	tileSize   int
+	cols, rows int
+	cells      [][]int
+}
+
+func buildTileGrid(geoms []Geom, width, height, tileSize int) *tileGrid {
+	cols := (width + tileSize - 1) / tileSize
+	rows := (height + tileSize - 1) / tileSize
+	grid := &tileGrid{tileSize: tileSize, cols: cols, rows: rows, cells: make([][]int, cols*rows)}
+
+	for i := range geoms {
+		grid.insert(i, geomAABB(&geoms[i]))
+	}
+	return grid
+}
+
+func (g *tileGrid) insert(i int, box AABB) {
+	if box.maxX < 0 || box.maxY < 0 || box.minX >= float64(g.cols*g.tileSize) || box.minY >= float64(g.rows*g.tileSize) {
+		return // entirely outside the canvas
+	}
+	x0 := intClamp(int(math.Floor(box.minX))/g.tileSize, 0, g.cols-1)
+	x1 := intClamp(int(math.Floor(box.maxX))/g.tileSize, 0, g.cols-1)
+	y0 := intClamp(int(math.Floor(box.minY))/g.tileSize, 0, g.rows-1)
+	y1 := intClamp(int(math.Floor(box.maxY))/g.tileSize, 0, g.rows-1)
+	for ty := y0; ty <= y1; ty++ {
+		for tx := x0; tx <= x1; tx++ {
+			idx := ty*g.cols + tx
+			g.cells[idx] = append(g.cells[idx], i)
+		}
+	}
+}
+
+func (g *tileGrid) indices(tx, ty int) []int {
+	return g.cells[ty*g.cols+tx]
+}
+
+func intClamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// renderTiles rasterizes geoms into a straight-alpha RGBA buffer, 4 bytes
+// per pixel, row-major from the top-left, the same layout the simple
+// single-goroutine renderer used to produce. The canvas is split into
+// opts.Tile-sized tiles (default 32px), each culled against a uniform
+// grid over the scene's geometry AABBs, and dispatched across
+// opts.Workers goroutines (default runtime.NumCPU()).
+func renderTiles(geoms []Geom, width, height int, opts RenderOptions) []byte {
+	tileSize := opts.Tile
+	if tileSize <= 0 {
+		tileSize = defaultTileSize
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	grid := buildTileGrid(geoms, width, height, tileSize)
+	pixels := make([]byte, width*height*4)
+
+	type tileCoord struct{ tx, ty int }
+	jobs := make(chan tileCoord, grid.cols*grid.rows)
+	for ty := 0; ty < grid.rows; ty++ {
+		for tx := 0; tx < grid.cols; tx++ {
+			jobs <- tileCoord{tx, ty}
+		}
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				renderTile(geoms, grid, pixels, width, height, tileSize, job.tx, job.ty)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return pixels
+}
+
+func renderTile(geoms []Geom, grid *tileGrid, pixels []byte, width, height, tileSize, tx, ty int) {
+	indices := grid.indices(tx, ty)
+
+	x0, y0 := tx*tileSize, ty*tileSize
+	x1, y1 := x0+tileSize, y0+tileSize
+	if x1 > width {
+		x1 = width
+	}
+	if y1 > height {
+		y1 = height
+	}
+
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			color, a := sdRender(geoms, indices, float64(x), float64(y))
+			i := (y*width + x) * 4
+			pixels[i+0] = color[0]
+			pixels[i+1] = color[1]
+			pixels[i+2] = color[2]
+			pixels[i+3] = byte(a * 255)
+		}
+	}
+}
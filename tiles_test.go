@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// benchSegments builds n deterministic segments spread across the canvas
+// so the tile grid sees realistic, non-overlapping occupancy.
+func benchSegments(n int) []Geom {
+	geoms := make([]Geom, n)
+	for i := 0; i < n; i++ {
+		t := float64(i) / float64(n)
+		a := Point{t * CanvasWidth, math.Mod(t*977, 1) * CanvasHeight}
+		b := Point{a.x + 10, a.y + 10}
+		geoms[i] = Geom{geomType: SegmentType, segment: Segment{a: a, b: b}, roundR: 1}
+	}
+	return geoms
+}
+
+func BenchmarkRenderTiles(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		geoms := benchSegments(n)
+		b.Run(fmt.Sprintf("segments=%d", n), func(b *testing.B) {
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				renderTiles(geoms, CanvasWidth, CanvasHeight, RenderOptions{})
+			}
+			pixelsPerRun := float64(CanvasWidth * CanvasHeight)
+			b.ReportMetric(pixelsPerRun*float64(b.N)/b.Elapsed().Seconds(), "pixels/sec")
+		})
+	}
+}